@@ -0,0 +1,36 @@
+package pipe
+
+import (
+	"math/rand"
+	"time"
+)
+
+// backoff computes exponentially increasing sleep durations with jitter,
+// used when GetRecords returns no data so we don't hammer the stream.
+type backoff struct {
+	base    time.Duration
+	max     time.Duration
+	attempt int
+}
+
+func newBackoff(base, max time.Duration) *backoff {
+	return &backoff{base: base, max: max}
+}
+
+// Next returns the duration to sleep for the current attempt and advances
+// the attempt counter.
+func (b *backoff) Next() time.Duration {
+	d := b.base * time.Duration(1<<uint(b.attempt))
+	if d <= 0 || d > b.max {
+		d = b.max
+	}
+	b.attempt++
+	// full jitter: sleep somewhere between 0 and d.
+	return time.Duration(rand.Int63n(int64(d)))
+}
+
+// Reset returns the backoff to its initial state, used once GetRecords
+// starts returning data again.
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
@@ -0,0 +1,45 @@
+package pipe
+
+import (
+	"testing"
+	"time"
+)
+
+func TestBackoffNextCapsAtMax(t *testing.T) {
+	b := newBackoff(100*time.Millisecond, 1*time.Second)
+	for i := 0; i < 10; i++ {
+		if d := b.Next(); d > 1*time.Second {
+			t.Fatalf("attempt %d: Next() = %s, want <= max (1s)", i, d)
+		}
+	}
+}
+
+func TestBackoffNextGrowsWithAttempt(t *testing.T) {
+	b := newBackoff(10*time.Millisecond, 1*time.Hour)
+	// Full jitter means Next() isn't monotonic itself, but its ceiling
+	// (base*2^attempt) must grow, so the max seen over many samples should
+	// climb with the attempt count.
+	var maxAtAttempt0, maxAtAttempt4 time.Duration
+	for i := 0; i < 200; i++ {
+		b.attempt = 0
+		if d := b.Next(); d > maxAtAttempt0 {
+			maxAtAttempt0 = d
+		}
+		b.attempt = 4
+		if d := b.Next(); d > maxAtAttempt4 {
+			maxAtAttempt4 = d
+		}
+	}
+	if maxAtAttempt4 <= maxAtAttempt0 {
+		t.Fatalf("expected later attempts to allow longer sleeps, got attempt0=%s attempt4=%s", maxAtAttempt0, maxAtAttempt4)
+	}
+}
+
+func TestBackoffReset(t *testing.T) {
+	b := newBackoff(10*time.Millisecond, 1*time.Hour)
+	b.attempt = 5
+	b.Reset()
+	if b.attempt != 0 {
+		t.Fatalf("attempt = %d after Reset, want 0", b.attempt)
+	}
+}
@@ -0,0 +1,189 @@
+package pipe
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// DefaultBatchMaxWait is how long a partially-filled batch waits for more
+// records before being flushed anyway.
+const DefaultBatchMaxWait = 1 * time.Second
+
+// Sink receives batches of records dispatched off the stream. Concrete
+// implementations live in the sinks subpackage.
+type Sink interface {
+	// Write delivers a batch of records. Order within a shard is
+	// preserved; order across shards is not guaranteed. Implementations
+	// should give up and return ctx.Err() once ctx is cancelled.
+	Write(ctx context.Context, records []*Record) error
+	// Flush gives a Sink the chance to deliver any batch it may be
+	// holding internally. Called once on shutdown, after the last Write.
+	Flush(ctx context.Context) error
+	// Close releases resources held by the Sink (network connections,
+	// worker processes, ...). Called once on shutdown, after Flush.
+	Close() error
+}
+
+// BatchOptions controls how individual records are grouped before being
+// handed to a Sink.
+type BatchOptions struct {
+	// MaxRecords flushes the current batch once it reaches this many
+	// records. Defaults to 1 (one Sink.Write per record) if <= 0.
+	MaxRecords int
+	// MaxWait flushes the current batch after this much time has passed
+	// since its first record arrived, even if MaxRecords hasn't been
+	// reached. Defaults to DefaultBatchMaxWait if <= 0.
+	MaxWait time.Duration
+}
+
+// pendingRecord pairs a Record with the shard it was read from, so the
+// batcher can advance that shard's checkpoint once the batch containing
+// it is actually written, instead of as soon as it is buffered.
+type pendingRecord struct {
+	record  *Record
+	shardID string
+}
+
+// batcher groups records written concurrently by many shard goroutines
+// into fixed-size-or-timed batches before handing them to a Sink. It also
+// owns checkpoint advancement: a shard's checkpoint is only moved past a
+// record once the batch containing it has been confirmed written, so a
+// crash while records sit in pending can never leave the checkpoint
+// ahead of what the Sink actually has.
+type batcher struct {
+	sink         Sink
+	checkpointer Checkpointer
+	maxRecords   int
+	maxWait      time.Duration
+
+	mu      sync.Mutex
+	pending []pendingRecord
+	timer   *time.Timer
+}
+
+func newBatcher(sink Sink, checkpointer Checkpointer, opts BatchOptions) *batcher {
+	maxRecords := opts.MaxRecords
+	if maxRecords <= 0 {
+		maxRecords = 1
+	}
+	maxWait := opts.MaxWait
+	if maxWait <= 0 {
+		maxWait = DefaultBatchMaxWait
+	}
+	return &batcher{
+		sink:         sink,
+		checkpointer: checkpointer,
+		maxRecords:   maxRecords,
+		maxWait:      maxWait,
+	}
+}
+
+// Add appends r to the pending batch, flushing immediately if the batch
+// is now full. shardID's checkpoint is not advanced here; it only moves
+// once the batch r ends up in has been written, see write.
+func (b *batcher) Add(ctx context.Context, shardID string, r *Record) error {
+	b.mu.Lock()
+	b.pending = append(b.pending, pendingRecord{record: r, shardID: shardID})
+	full := len(b.pending) >= b.maxRecords
+	if len(b.pending) == 1 && !full {
+		b.timer = time.AfterFunc(b.maxWait, func() { b.flushOnTimer(ctx) })
+	}
+	var batch []pendingRecord
+	if full {
+		batch = b.take()
+	}
+	b.mu.Unlock()
+	if batch == nil {
+		return nil
+	}
+	return b.write(ctx, batch)
+}
+
+// flushOnTimer is invoked off the timer goroutine, so a write failure has
+// no caller to return to; it is logged instead, same as the rest of this
+// tool's fire-and-forget background loops.
+func (b *batcher) flushOnTimer(ctx context.Context) {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+	if batch == nil {
+		return
+	}
+	if err := b.write(ctx, batch); err != nil {
+		slog.ErrorContext(ctx, "failed to flush batch", "error", err)
+	}
+}
+
+// write delivers batch to the Sink, recording write latency and error
+// metrics around the call, then advances each shard's checkpoint past
+// the records in batch -- but only once the Sink has confirmed it has
+// them, so a checkpoint never outruns what was actually delivered.
+func (b *batcher) write(ctx context.Context, batch []pendingRecord) error {
+	records := make([]*Record, len(batch))
+	for i, pr := range batch {
+		records[i] = pr.record
+	}
+	start := time.Now()
+	err := b.sink.Write(ctx, records)
+	sinkWriteDurationSeconds.Observe(time.Since(start).Seconds())
+	if err != nil {
+		sinkWriteErrorsTotal.Inc()
+		return err
+	}
+	return b.checkpoint(ctx, batch)
+}
+
+// checkpoint advances each shard represented in batch to the sequence
+// number of its last record in batch (records within a shard arrive in
+// order, so the last one seen is the highest).
+func (b *batcher) checkpoint(ctx context.Context, batch []pendingRecord) error {
+	order := make([]string, 0, len(batch))
+	last := make(map[string]string, len(batch))
+	for _, pr := range batch {
+		if _, ok := last[pr.shardID]; !ok {
+			order = append(order, pr.shardID)
+		}
+		last[pr.shardID] = aws.ToString(pr.record.Dynamodb.SequenceNumber)
+	}
+	for _, shardID := range order {
+		if err := b.checkpointer.Set(ctx, shardID, last[shardID]); err != nil {
+			return err
+		}
+		checkpointCommitsTotal.Inc()
+	}
+	return nil
+}
+
+// take returns and clears the pending batch. Callers must hold b.mu.
+func (b *batcher) take() []pendingRecord {
+	if len(b.pending) == 0 {
+		return nil
+	}
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	batch := b.pending
+	b.pending = nil
+	return batch
+}
+
+// Close flushes any pending batch, then flushes and closes the Sink.
+func (b *batcher) Close(ctx context.Context) error {
+	b.mu.Lock()
+	batch := b.take()
+	b.mu.Unlock()
+	if batch != nil {
+		if err := b.write(ctx, batch); err != nil {
+			return err
+		}
+	}
+	if err := b.sink.Flush(ctx); err != nil {
+		return err
+	}
+	return b.sink.Close()
+}
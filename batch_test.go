@@ -0,0 +1,144 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+type fakeSink struct {
+	mu      sync.Mutex
+	batches [][]*Record
+	err     error
+}
+
+func (s *fakeSink) Write(ctx context.Context, records []*Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	s.batches = append(s.batches, records)
+	return nil
+}
+
+func (s *fakeSink) Flush(ctx context.Context) error { return nil }
+func (s *fakeSink) Close() error                    { return nil }
+
+func (s *fakeSink) writeCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.batches)
+}
+
+func recordWithSeq(seq string) *Record {
+	return &Record{Dynamodb: &StreamRecord{SequenceNumber: aws.String(seq)}}
+}
+
+func TestBatcherAddDoesNotCheckpointBeforeFlush(t *testing.T) {
+	sink := &fakeSink{}
+	checkpointer := newMemCheckpointer()
+	b := newBatcher(sink, checkpointer, BatchOptions{MaxRecords: 2, MaxWait: time.Hour})
+	ctx := context.Background()
+
+	if err := b.Add(ctx, "shard-0", recordWithSeq("100")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if sink.writeCount() != 0 {
+		t.Fatalf("sink.Write called before batch is full")
+	}
+	if seq, _ := checkpointer.Get(ctx, "shard-0"); seq != "" {
+		t.Fatalf("checkpoint advanced to %q before the record was ever written to the sink", seq)
+	}
+}
+
+func TestBatcherAddCheckpointsOnlyAfterFlush(t *testing.T) {
+	sink := &fakeSink{}
+	checkpointer := newMemCheckpointer()
+	b := newBatcher(sink, checkpointer, BatchOptions{MaxRecords: 2, MaxWait: time.Hour})
+	ctx := context.Background()
+
+	if err := b.Add(ctx, "shard-0", recordWithSeq("100")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := b.Add(ctx, "shard-0", recordWithSeq("200")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if sink.writeCount() != 1 {
+		t.Fatalf("writeCount = %d, want 1 after the batch filled up", sink.writeCount())
+	}
+	seq, err := checkpointer.Get(ctx, "shard-0")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if seq != "200" {
+		t.Fatalf("checkpoint = %q, want the last flushed sequence number (200)", seq)
+	}
+}
+
+func TestBatcherFailedWriteDoesNotCheckpoint(t *testing.T) {
+	sink := &fakeSink{err: errors.New("sink down")}
+	checkpointer := newMemCheckpointer()
+	b := newBatcher(sink, checkpointer, BatchOptions{MaxRecords: 1, MaxWait: time.Hour})
+	ctx := context.Background()
+
+	if err := b.Add(ctx, "shard-0", recordWithSeq("100")); err == nil {
+		t.Fatal("Add: expected an error from the failing sink")
+	}
+	if seq, _ := checkpointer.Get(ctx, "shard-0"); seq != "" {
+		t.Fatalf("checkpoint advanced to %q despite the sink write failing", seq)
+	}
+}
+
+func TestBatcherFlushesOnMaxWait(t *testing.T) {
+	sink := &fakeSink{}
+	checkpointer := newMemCheckpointer()
+	b := newBatcher(sink, checkpointer, BatchOptions{MaxRecords: 10, MaxWait: 10 * time.Millisecond})
+	ctx := context.Background()
+
+	if err := b.Add(ctx, "shard-0", recordWithSeq("100")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if sink.writeCount() == 1 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if sink.writeCount() != 1 {
+		t.Fatalf("writeCount = %d, want 1 after MaxWait elapsed", sink.writeCount())
+	}
+	if seq, _ := checkpointer.Get(ctx, "shard-0"); seq != "100" {
+		t.Fatalf("checkpoint = %q, want 100 after the timer-triggered flush", seq)
+	}
+}
+
+func TestBatcherCheckpointsMultipleShardsIndependently(t *testing.T) {
+	sink := &fakeSink{}
+	checkpointer := newMemCheckpointer()
+	b := newBatcher(sink, checkpointer, BatchOptions{MaxRecords: 3, MaxWait: time.Hour})
+	ctx := context.Background()
+
+	if err := b.Add(ctx, "shard-0", recordWithSeq("1")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := b.Add(ctx, "shard-1", recordWithSeq("50")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := b.Add(ctx, "shard-0", recordWithSeq("2")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if seq, _ := checkpointer.Get(ctx, "shard-0"); seq != "2" {
+		t.Fatalf("shard-0 checkpoint = %q, want 2", seq)
+	}
+	if seq, _ := checkpointer.Get(ctx, "shard-1"); seq != "50" {
+		t.Fatalf("shard-1 checkpoint = %q, want 50", seq)
+	}
+}
@@ -0,0 +1,107 @@
+package pipe
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// A Checkpointer persists the last processed sequence number for each shard
+// so that a restart can resume with GetShardIterator/AFTER_SEQUENCE_NUMBER
+// instead of re-reading the whole stream from TRIM_HORIZON.
+type Checkpointer interface {
+	// Get returns the last checkpointed sequence number for shardID, or ""
+	// if no checkpoint has been recorded yet.
+	Get(ctx context.Context, shardID string) (string, error)
+
+	// Set records sequenceNumber as the last processed record for shardID.
+	Set(ctx context.Context, shardID, sequenceNumber string) error
+}
+
+// memCheckpointer is the zero-value Checkpointer used when the caller
+// doesn't supply one: it never persists anything, so every shard always
+// starts from TRIM_HORIZON.
+type memCheckpointer struct {
+	mu   sync.Mutex
+	seqs map[string]string
+}
+
+func newMemCheckpointer() *memCheckpointer {
+	return &memCheckpointer{seqs: map[string]string{}}
+}
+
+func (c *memCheckpointer) Get(ctx context.Context, shardID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seqs[shardID], nil
+}
+
+func (c *memCheckpointer) Set(ctx context.Context, shardID, sequenceNumber string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seqs[shardID] = sequenceNumber
+	return nil
+}
+
+// FileCheckpointer persists checkpoints as a JSON object on the local
+// filesystem. It is the simplest Checkpointer and is suitable for
+// single-instance deployments.
+type FileCheckpointer struct {
+	path string
+
+	mu   sync.Mutex
+	seqs map[string]string
+}
+
+// NewFileCheckpointer loads checkpoints from path if it exists, creating
+// an empty checkpoint set otherwise.
+func NewFileCheckpointer(path string) (*FileCheckpointer, error) {
+	c := &FileCheckpointer{
+		path: path,
+		seqs: map[string]string{},
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return c, nil
+		}
+		return nil, errors.Wrap(err, "failed to read checkpoint file")
+	}
+	if len(b) == 0 {
+		return c, nil
+	}
+	if err := json.Unmarshal(b, &c.seqs); err != nil {
+		return nil, errors.Wrap(err, "failed to parse checkpoint file")
+	}
+	return c, nil
+}
+
+// Get implements Checkpointer.
+func (c *FileCheckpointer) Get(ctx context.Context, shardID string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.seqs[shardID], nil
+}
+
+// Set implements Checkpointer.
+func (c *FileCheckpointer) Set(ctx context.Context, shardID, sequenceNumber string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.seqs[shardID] = sequenceNumber
+	b, err := json.MarshalIndent(c.seqs, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal checkpoints")
+	}
+	tmp := c.path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return errors.Wrap(err, "failed to write checkpoint file")
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return errors.Wrap(err, "failed to replace checkpoint file")
+	}
+	return nil
+}
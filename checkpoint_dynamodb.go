@@ -0,0 +1,65 @@
+package pipe
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/pkg/errors"
+)
+
+// DynamoDBCheckpointer persists checkpoints in a DynamoDB table, keyed by
+// shard id. It is suitable for multi-instance deployments where the
+// checkpoint state must be shared. The table must have a string hash key
+// named "ShardId".
+type DynamoDBCheckpointer struct {
+	d     *dynamodb.Client
+	table string
+}
+
+// NewDynamoDBCheckpointer returns a Checkpointer backed by the given
+// DynamoDB table.
+func NewDynamoDBCheckpointer(d *dynamodb.Client, table string) *DynamoDBCheckpointer {
+	return &DynamoDBCheckpointer{
+		d:     d,
+		table: table,
+	}
+}
+
+// Get implements Checkpointer.
+func (c *DynamoDBCheckpointer) Get(ctx context.Context, shardID string) (string, error) {
+	out, err := c.d.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(c.table),
+		Key: map[string]types.AttributeValue{
+			"ShardId": &types.AttributeValueMemberS{Value: shardID},
+		},
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get checkpoint item")
+	}
+	if out.Item == nil {
+		return "", nil
+	}
+	seq, ok := out.Item["SequenceNumber"].(*types.AttributeValueMemberS)
+	if !ok {
+		return "", nil
+	}
+	return seq.Value, nil
+}
+
+// Set implements Checkpointer.
+func (c *DynamoDBCheckpointer) Set(ctx context.Context, shardID, sequenceNumber string) error {
+	_, err := c.d.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName: aws.String(c.table),
+		Item: map[string]types.AttributeValue{
+			"ShardId":        &types.AttributeValueMemberS{Value: shardID},
+			"SequenceNumber": &types.AttributeValueMemberS{Value: sequenceNumber},
+		},
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to put checkpoint item")
+	}
+	return nil
+}
@@ -1,10 +1,19 @@
 package main
 
 import (
-	"log"
+	"context"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
 	dp "github.com/gino8070/dynamodb-stream-pipe"
+	"github.com/gino8070/dynamodb-stream-pipe/sinks"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	kingpin "gopkg.in/alecthomas/kingpin.v2"
 )
 
@@ -15,18 +24,165 @@ func main() {
 func _main() int {
 	endpoint := kingpin.Flag("endpoint", "dynamodb endpoint(optional).").String()
 	table := kingpin.Flag("table", "dynamodb table name.").Required().String()
-	command := kingpin.Flag("command", "execute command. ex --command=wc").Required().String()
-	args := kingpin.Flag("args", "comma separated command args(optional). ex --args=-l").String()
+	maxConcurrentShards := kingpin.Flag("max-concurrent-shards", "maximum number of shards processed at once.").Default("8").Int()
+	pollInterval := kingpin.Flag("poll-interval", "how often to re-poll DescribeStream for new shards.").Default("30s").Duration()
+	emptyBackoffBase := kingpin.Flag("empty-backoff-base", "initial backoff between GetRecords calls when a shard has no data.").Default("250ms").Duration()
+	emptyBackoffMax := kingpin.Flag("empty-backoff-max", "max backoff between GetRecords calls when a shard has no data.").Default("30s").Duration()
+	checkpointPath := kingpin.Flag("checkpoint-path", "file to persist shard checkpoints in(optional, mutually exclusive with checkpoint-table).").String()
+	checkpointTable := kingpin.Flag("checkpoint-table", "dynamodb table to persist shard checkpoints in(optional, mutually exclusive with checkpoint-path).").String()
+
+	sink := kingpin.Flag("sink", "where to deliver records.").Default("exec").Enum("exec", "exec-batch", "http", "sqs", "kinesis", "stdout")
+	batchSize := kingpin.Flag("batch-size", "number of records to group into a single sink write(optional).").Default("1").Int()
+	batchWindow := kingpin.Flag("batch-window", "max time to wait for batch-size records before flushing a partial batch(optional).").Default("1s").Duration()
+
+	command := kingpin.Flag("command", "execute command(sink=exec|exec-batch). ex --command=wc").String()
+	args := kingpin.Flag("args", "comma separated command args(sink=exec|exec-batch, optional). ex --args=-l").String()
+	httpURL := kingpin.Flag("http-url", "webhook url(sink=http).").String()
+	httpMethod := kingpin.Flag("http-method", "webhook http method(sink=http, optional).").Default("POST").String()
+	httpHeader := kingpin.Flag("http-header", "webhook header as Key:Value(sink=http, optional, repeatable).").Strings()
+	httpRetries := kingpin.Flag("http-retries", "webhook retry attempts on error(sink=http, optional).").Default("2").Int()
+	sqsEndpoint := kingpin.Flag("sqs-endpoint", "sqs endpoint(sink=sqs, optional).").String()
+	sqsQueueURL := kingpin.Flag("sqs-queue-url", "sqs queue url(sink=sqs).").String()
+	kinesisEndpoint := kingpin.Flag("kinesis-endpoint", "kinesis endpoint(sink=kinesis, optional).").String()
+	kinesisStreamName := kingpin.Flag("kinesis-stream-name", "kinesis stream name(sink=kinesis).").String()
+
+	outputFormat := kingpin.Flag("output-format", "how to render Keys/NewImage/OldImage.").Default("ddb-json").Enum("ddb-json", "plain-json", "both")
+	filterEventName := kingpin.Flag("filter-event-name", "comma separated eventName values to keep(optional). ex --filter-event-name=INSERT,MODIFY").String()
+	filterExpr := kingpin.Flag("filter-expr", "jmespath expression evaluated against the plain-json record; falsy results are dropped(optional).").String()
+	metricsAddr := kingpin.Flag("metrics-addr", "address to serve /metrics on(optional, e.g. :9090). Leave unset to disable.").String()
 	kingpin.Parse()
 
-	app, err := dp.NewApp(*endpoint, *table, *command, *args)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if *metricsAddr != "" {
+		go serveMetrics(ctx, *metricsAddr)
+	}
+
+	s, err := newSink(ctx, *sink, *command, *args, *httpURL, *httpMethod, *httpHeader, *httpRetries, *sqsEndpoint, *sqsQueueURL, *kinesisEndpoint, *kinesisStreamName)
+	if err != nil {
+		slog.Error(err.Error())
+		return 1
+	}
+
+	var filterEventNames []string
+	if *filterEventName != "" {
+		filterEventNames = strings.Split(*filterEventName, ",")
+	}
+
+	opts := dp.Options{
+		Endpoint: *endpoint,
+		Table:    *table,
+		Sink:     s,
+		Batch: dp.BatchOptions{
+			MaxRecords: *batchSize,
+			MaxWait:    *batchWindow,
+		},
+		MaxConcurrentShards: *maxConcurrentShards,
+		PollInterval:        *pollInterval,
+		BackoffBase:         *emptyBackoffBase,
+		BackoffMax:          *emptyBackoffMax,
+		CheckpointTable:     *checkpointTable,
+		OutputFormat:        dp.OutputFormat(*outputFormat),
+		FilterEventNames:    filterEventNames,
+		FilterExpr:          *filterExpr,
+	}
+	if *checkpointPath != "" && *checkpointTable != "" {
+		slog.Error("--checkpoint-path and --checkpoint-table are mutually exclusive")
+		return 1
+	}
+	if *checkpointPath != "" {
+		cp, err := dp.NewFileCheckpointer(*checkpointPath)
+		if err != nil {
+			slog.Error(err.Error())
+			return 1
+		}
+		opts.Checkpointer = cp
+	}
+
+	app, err := dp.NewApp(ctx, opts)
 	if err != nil {
-		log.Println(err)
+		slog.Error(err.Error())
 		return 1
 	}
-	if err = app.Run(); err != nil {
-		log.Println(err)
+	// Run blocks until ctx is cancelled by SIGINT/SIGTERM above, draining
+	// in-flight records and committing checkpoints before returning.
+	if err = app.Run(ctx); err != nil {
+		slog.Error(err.Error())
 		return 1
 	}
 	return 0
 }
+
+// serveMetrics exposes /metrics on addr until ctx is cancelled. Errors
+// are logged rather than returned since it runs detached from the main
+// pipeline goroutine.
+func serveMetrics(ctx context.Context, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	srv := &http.Server{Addr: addr, Handler: mux}
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		srv.Shutdown(shutdownCtx)
+	}()
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		slog.Error("metrics server failed", "error", err)
+	}
+}
+
+// splitArgs splits a comma separated --args value into its parts,
+// returning nil for an unset/empty value instead of strings.Split's
+// []string{""}, which would otherwise append a bogus empty argument to
+// the command.
+func splitArgs(args string) []string {
+	if args == "" {
+		return nil
+	}
+	return strings.Split(args, ",")
+}
+
+func newSink(ctx context.Context, sink, command, args, httpURL, httpMethod string, httpHeaders []string, httpRetries int, sqsEndpoint, sqsQueueURL, kinesisEndpoint, kinesisStreamName string) (dp.Sink, error) {
+	switch sink {
+	case "exec":
+		if command == "" {
+			return nil, errors.New("--command is required for --sink=exec")
+		}
+		return sinks.NewExec(command, splitArgs(args)), nil
+	case "exec-batch":
+		if command == "" {
+			return nil, errors.New("--command is required for --sink=exec-batch")
+		}
+		return sinks.NewExecBatch(command, splitArgs(args))
+	case "http":
+		if httpURL == "" {
+			return nil, errors.New("--http-url is required for --sink=http")
+		}
+		headers := map[string]string{}
+		for _, h := range httpHeaders {
+			kv := strings.SplitN(h, ":", 2)
+			if len(kv) != 2 {
+				return nil, errors.Errorf("invalid --http-header %q, want Key:Value", h)
+			}
+			headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+		return sinks.NewHTTP(httpURL, httpMethod, headers, httpRetries), nil
+	case "sqs":
+		if sqsQueueURL == "" {
+			return nil, errors.New("--sqs-queue-url is required for --sink=sqs")
+		}
+		return sinks.NewSQS(ctx, sqsEndpoint, sqsQueueURL)
+	case "kinesis":
+		if kinesisStreamName == "" {
+			return nil, errors.New("--kinesis-stream-name is required for --sink=kinesis")
+		}
+		return sinks.NewKinesis(ctx, kinesisEndpoint, kinesisStreamName)
+	case "stdout":
+		return sinks.NewStdout(os.Stdout), nil
+	default:
+		return nil, errors.Errorf("unknown sink %q", sink)
+	}
+}
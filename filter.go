@@ -0,0 +1,162 @@
+package pipe
+
+import (
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/jmespath/go-jmespath"
+	"github.com/pkg/errors"
+)
+
+// Filter decides whether a record should be handed to the Sink at all,
+// letting callers skip uninteresting events before paying for a sink
+// write (an exec fork, an HTTP call, ...) -- mirroring how Lambda event
+// filters work for DynamoDB Streams triggers.
+type Filter struct {
+	eventNames map[types.OperationType]bool
+	expr       *jmespath.JMESPath
+}
+
+// NewFilter compiles eventNames and expr into a Filter. Either may be
+// empty, in which case that criterion always passes. expr is a JMESPath
+// expression evaluated against the record's plain-json representation,
+// regardless of the App's configured OutputFormat; a record is kept
+// unless the expression evaluates to a falsy result (nil, false, "",
+// an empty list or an empty map).
+func NewFilter(eventNames []string, expr string) (*Filter, error) {
+	if len(eventNames) == 0 && expr == "" {
+		return nil, nil
+	}
+	f := &Filter{}
+	if len(eventNames) > 0 {
+		f.eventNames = make(map[types.OperationType]bool, len(eventNames))
+		for _, n := range eventNames {
+			f.eventNames[types.OperationType(n)] = true
+		}
+	}
+	if expr != "" {
+		compiled, err := jmespath.Compile(expr)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to compile filter expression")
+		}
+		f.expr = compiled
+	}
+	return f, nil
+}
+
+// Keep reports whether r passes the filter.
+func (f *Filter) Keep(r types.Record) (bool, error) {
+	if f == nil {
+		return true, nil
+	}
+	if f.eventNames != nil && !f.eventNames[r.EventName] {
+		return false, nil
+	}
+	if f.expr == nil {
+		return true, nil
+	}
+	result, err := f.expr.Search(newPlainRecordMap(r))
+	if err != nil {
+		return false, errors.Wrap(err, "failed to evaluate filter expression")
+	}
+	return truthy(result), nil
+}
+
+// newPlainRecordMap renders r as the plain-json map a filter expression
+// is evaluated against, independent of the App's configured OutputFormat.
+//
+// Numeric attributes are rendered as float64 here rather than the
+// json.Number NewPlainAttributeValueMap uses for sink output: go-jmespath's
+// ordering comparators (<, <=, >, >=) type-assert both operands to
+// float64, so a json.Number would silently never match a numeric-threshold
+// filter expression.
+func newPlainRecordMap(r types.Record) map[string]interface{} {
+	m := map[string]interface{}{
+		"eventID":      aws.ToString(r.EventID),
+		"eventName":    string(r.EventName),
+		"eventSource":  aws.ToString(r.EventSource),
+		"eventVersion": aws.ToString(r.EventVersion),
+		"awsRegion":    aws.ToString(r.AwsRegion),
+	}
+	if r.Dynamodb != nil {
+		m["dynamodb"] = map[string]interface{}{
+			"Keys":           newFilterAttributeValueMap(r.Dynamodb.Keys),
+			"NewImage":       newFilterAttributeValueMap(r.Dynamodb.NewImage),
+			"OldImage":       newFilterAttributeValueMap(r.Dynamodb.OldImage),
+			"StreamViewType": string(r.Dynamodb.StreamViewType),
+		}
+	}
+	return m
+}
+
+// newFilterAttributeValue mirrors NewPlainAttributeValue, except N/NS
+// attributes are converted to float64/[]float64 instead of json.Number so
+// they work with go-jmespath's numeric comparators.
+func newFilterAttributeValue(a types.AttributeValue) interface{} {
+	switch v := a.(type) {
+	case *types.AttributeValueMemberN:
+		return parseFilterNumber(v.Value)
+	case *types.AttributeValueMemberNS:
+		ns := make([]float64, len(v.Value))
+		for i, n := range v.Value {
+			ns[i] = parseFilterNumber(n)
+		}
+		return ns
+	case *types.AttributeValueMemberL:
+		return newFilterAttributeValueList(v.Value)
+	case *types.AttributeValueMemberM:
+		return newFilterAttributeValueMap(v.Value)
+	default:
+		return NewPlainAttributeValue(a)
+	}
+}
+
+func newFilterAttributeValueMap(m map[string]types.AttributeValue) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	r := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		r[k] = newFilterAttributeValue(v)
+	}
+	return r
+}
+
+func newFilterAttributeValueList(l []types.AttributeValue) []interface{} {
+	r := make([]interface{}, len(l))
+	for idx, a := range l {
+		r[idx] = newFilterAttributeValue(a)
+	}
+	return r
+}
+
+// parseFilterNumber parses a DynamoDB N attribute's decimal string into a
+// float64. DynamoDB only ever writes valid decimal numbers into N, so a
+// parse failure (e.g. a value outside float64's range) falls back to 0
+// rather than failing the whole filter evaluation. Digits beyond
+// float64's precision are rounded, not treated as a failure.
+func parseFilterNumber(s string) float64 {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return f
+}
+
+func truthy(v interface{}) bool {
+	switch vv := v.(type) {
+	case nil:
+		return false
+	case bool:
+		return vv
+	case string:
+		return vv != ""
+	case []interface{}:
+		return len(vv) > 0
+	case map[string]interface{}:
+		return len(vv) > 0
+	default:
+		return true
+	}
+}
@@ -0,0 +1,90 @@
+package pipe
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+)
+
+func recordForFilter(eventName types.OperationType, amount string) types.Record {
+	return types.Record{
+		EventName: eventName,
+		Dynamodb: &types.StreamRecord{
+			NewImage: map[string]types.AttributeValue{
+				"amount": &types.AttributeValueMemberN{Value: amount},
+			},
+		},
+	}
+}
+
+func TestFilterKeepByEventName(t *testing.T) {
+	f, err := NewFilter([]string{"INSERT", "MODIFY"}, "")
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+
+	keep, err := f.Keep(recordForFilter(types.OperationTypeInsert, "1"))
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	if !keep {
+		t.Fatal("INSERT should pass an INSERT/MODIFY filter")
+	}
+
+	keep, err = f.Keep(recordForFilter(types.OperationTypeRemove, "1"))
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	if keep {
+		t.Fatal("REMOVE should not pass an INSERT/MODIFY filter")
+	}
+}
+
+func TestFilterKeepByNumericThreshold(t *testing.T) {
+	f, err := NewFilter(nil, "dynamodb.NewImage.amount > `100`")
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+
+	keep, err := f.Keep(recordForFilter(types.OperationTypeInsert, "150"))
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	if !keep {
+		t.Fatal("150 > 100 should pass the filter")
+	}
+
+	keep, err = f.Keep(recordForFilter(types.OperationTypeInsert, "50"))
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	if keep {
+		t.Fatal("50 > 100 should not pass the filter")
+	}
+}
+
+func TestFilterKeepCombinesEventNameAndExpr(t *testing.T) {
+	f, err := NewFilter([]string{"INSERT"}, "dynamodb.NewImage.amount > `100`")
+	if err != nil {
+		t.Fatalf("NewFilter: %v", err)
+	}
+
+	keep, err := f.Keep(recordForFilter(types.OperationTypeModify, "150"))
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	if keep {
+		t.Fatal("MODIFY should be dropped before the expr is even evaluated")
+	}
+}
+
+func TestFilterNilPassesEverything(t *testing.T) {
+	var f *Filter
+	keep, err := f.Keep(recordForFilter(types.OperationTypeRemove, "1"))
+	if err != nil {
+		t.Fatalf("Keep: %v", err)
+	}
+	if !keep {
+		t.Fatal("a nil Filter should keep every record")
+	}
+}
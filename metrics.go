@@ -0,0 +1,52 @@
+package pipe
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Metrics are registered against the default Prometheus registry so
+// cmd/pipe can expose them with a plain promhttp.Handler() behind
+// --metrics-addr, without the pipe package needing to know about HTTP.
+var (
+	recordsProcessedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dynamodb_stream_pipe_records_processed_total",
+		Help: "Number of stream records handed to the sink, by eventName.",
+	}, []string{"event_name"})
+
+	iteratorAgeSeconds = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "dynamodb_stream_pipe_iterator_age_seconds",
+		Help: "Age of the most recently processed record in a shard, computed from ApproximateCreationDateTime. The critical lag signal for a DynamoDB Streams consumer.",
+	}, []string{"shard_id"})
+
+	getRecordsDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "dynamodb_stream_pipe_get_records_duration_seconds",
+		Help: "Latency of GetRecords calls against DynamoDB Streams.",
+	})
+
+	sinkWriteDurationSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name: "dynamodb_stream_pipe_sink_write_duration_seconds",
+		Help: "Latency of Sink.Write calls.",
+	})
+
+	sinkWriteErrorsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "dynamodb_stream_pipe_sink_write_errors_total",
+		Help: "Number of Sink.Write calls that returned an error.",
+	})
+
+	checkpointCommitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "dynamodb_stream_pipe_checkpoint_commits_total",
+		Help: "Number of successful Checkpointer.Set calls.",
+	})
+)
+
+// observeIteratorAge records how far behind now a shard's most recently
+// processed record is.
+func observeIteratorAge(shardID string, approximateCreationDateTime *time.Time) {
+	if approximateCreationDateTime == nil {
+		return
+	}
+	iteratorAgeSeconds.WithLabelValues(shardID).Set(time.Since(*approximateCreationDateTime).Seconds())
+}
@@ -0,0 +1,84 @@
+package pipe
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	dto "github.com/prometheus/client_model/go"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func histogramSampleCount(t *testing.T, h interface {
+	Write(*dto.Metric) error
+}) uint64 {
+	t.Helper()
+	var m dto.Metric
+	if err := h.Write(&m); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	return m.GetHistogram().GetSampleCount()
+}
+
+func TestObserveIteratorAgeNilTimestampIsNoop(t *testing.T) {
+	before := testutil.CollectAndCount(iteratorAgeSeconds)
+	observeIteratorAge("shard-nil-timestamp", nil)
+	if after := testutil.CollectAndCount(iteratorAgeSeconds); after != before {
+		t.Fatalf("CollectAndCount = %d, want unchanged at %d after a nil timestamp", after, before)
+	}
+}
+
+func TestObserveIteratorAgeSetsGauge(t *testing.T) {
+	ts := time.Now().Add(-5 * time.Second)
+	observeIteratorAge("shard-with-timestamp", &ts)
+	age := testutil.ToFloat64(iteratorAgeSeconds.WithLabelValues("shard-with-timestamp"))
+	if age < 5 || age > 15 {
+		t.Fatalf("iteratorAgeSeconds = %v, want roughly 5s", age)
+	}
+}
+
+func TestBatcherFlushTouchesSinkWriteMetrics(t *testing.T) {
+	sink := &fakeSink{}
+	checkpointer := newMemCheckpointer()
+	b := newBatcher(sink, checkpointer, BatchOptions{MaxRecords: 1, MaxWait: time.Hour})
+	ctx := context.Background()
+
+	commitsBefore := testutil.ToFloat64(checkpointCommitsTotal)
+	errorsBefore := testutil.ToFloat64(sinkWriteErrorsTotal)
+	countBefore := histogramSampleCount(t, sinkWriteDurationSeconds)
+
+	if err := b.Add(ctx, "shard-0", recordWithSeq("100")); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if got := testutil.ToFloat64(checkpointCommitsTotal); got != commitsBefore+1 {
+		t.Fatalf("checkpointCommitsTotal = %v, want %v after a successful flush", got, commitsBefore+1)
+	}
+	if got := histogramSampleCount(t, sinkWriteDurationSeconds); got != countBefore+1 {
+		t.Fatalf("sinkWriteDurationSeconds observation count = %d, want %d after a flush", got, countBefore+1)
+	}
+	if got := testutil.ToFloat64(sinkWriteErrorsTotal); got != errorsBefore {
+		t.Fatalf("sinkWriteErrorsTotal = %v, want unchanged at %v after a successful flush", got, errorsBefore)
+	}
+}
+
+func TestBatcherFailedFlushTouchesErrorCounter(t *testing.T) {
+	sink := &fakeSink{err: errors.New("sink down")}
+	checkpointer := newMemCheckpointer()
+	b := newBatcher(sink, checkpointer, BatchOptions{MaxRecords: 1, MaxWait: time.Hour})
+	ctx := context.Background()
+
+	errorsBefore := testutil.ToFloat64(sinkWriteErrorsTotal)
+	commitsBefore := testutil.ToFloat64(checkpointCommitsTotal)
+
+	if err := b.Add(ctx, "shard-0", recordWithSeq("100")); err == nil {
+		t.Fatal("Add: expected an error from the failing sink")
+	}
+	if got := testutil.ToFloat64(sinkWriteErrorsTotal); got != errorsBefore+1 {
+		t.Fatalf("sinkWriteErrorsTotal = %v, want %v after a failed flush", got, errorsBefore+1)
+	}
+	if got := testutil.ToFloat64(checkpointCommitsTotal); got != commitsBefore {
+		t.Fatalf("checkpointCommitsTotal = %v, want unchanged at %v after a failed flush", got, commitsBefore)
+	}
+}
@@ -1,100 +1,207 @@
 package pipe
 
 import (
-	"encoding/json"
-	"io"
-	"log"
-	"os/exec"
-	"strings"
+	"context"
+	"log/slog"
 	"time"
 
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/session"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
 	"github.com/pkg/errors"
 )
 
+const (
+	// DefaultMaxConcurrentShards caps how many shards are drained at once.
+	DefaultMaxConcurrentShards = 8
+	// DefaultPollInterval is how often DescribeStream is re-polled for
+	// shards created by a resharding event.
+	DefaultPollInterval = 30 * time.Second
+	// DefaultBackoffBase is the initial sleep after an empty GetRecords.
+	DefaultBackoffBase = 250 * time.Millisecond
+	// DefaultBackoffMax caps the empty-GetRecords backoff.
+	DefaultBackoffMax = 30 * time.Second
+)
+
+// Options configures a App. Table and Sink are required; the rest fall
+// back to sane defaults.
+type Options struct {
+	// Endpoint overrides the DynamoDB/DynamoDB Streams endpoint, for
+	// pointing at a local DynamoDB instead of AWS.
+	Endpoint string
+	Table    string
+
+	// Sink receives every record read off the stream, grouped into
+	// batches per BatchOptions.
+	Sink Sink
+	// Batch controls how records are grouped before being handed to Sink.
+	Batch BatchOptions
+
+	// Checkpointer persists per-shard sequence numbers across restarts.
+	// Takes precedence over CheckpointTable. Defaults to an in-memory
+	// checkpointer (i.e. always TRIM_HORIZON) if neither is set.
+	Checkpointer Checkpointer
+	// CheckpointTable, if set and Checkpointer is nil, stores checkpoints
+	// in this DynamoDB table via a DynamoDBCheckpointer sharing the App's
+	// client.
+	CheckpointTable string
+
+	// MaxConcurrentShards caps how many shards are drained at once.
+	MaxConcurrentShards int
+	// PollInterval is how often DescribeStream is re-polled to discover
+	// shards created by resharding.
+	PollInterval time.Duration
+	// BackoffBase/BackoffMax bound the exponential backoff applied after
+	// an empty GetRecords response.
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+
+	// OutputFormat controls how Keys/NewImage/OldImage are rendered.
+	// Defaults to OutputFormatDDBJSON.
+	OutputFormat OutputFormat
+
+	// FilterEventNames, if non-empty, drops any record whose eventName
+	// isn't in the list (e.g. []string{"INSERT", "MODIFY"}).
+	FilterEventNames []string
+	// FilterExpr, if set, is a JMESPath expression evaluated against the
+	// record's plain-json representation; records for which it evaluates
+	// falsy are dropped before reaching Sink.
+	FilterExpr string
+}
+
 type App struct {
-	table   string
-	command string
-	args    []string
-	d       *dynamodb.DynamoDB
-	ds      *dynamodbstreams.DynamoDBStreams
+	table string
+	d     *dynamodb.Client
+	ds    *dynamodbstreams.Client
+
+	batcher             *batcher
+	checkpointer        Checkpointer
+	maxConcurrentShards int
+	pollInterval        time.Duration
+	backoffBase         time.Duration
+	backoffMax          time.Duration
+
+	outputFormat OutputFormat
+	filter       *Filter
 }
 
-func NewApp(endpoint, table, command, args string) (*App, error) {
-	sess := session.Must(session.NewSession(&aws.Config{
-		Endpoint: aws.String(endpoint),
-	}))
+func NewApp(ctx context.Context, opts Options) (*App, error) {
+	if opts.Sink == nil {
+		return nil, errors.New("no sink configured")
+	}
+
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load aws config")
+	}
+
+	d := dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	})
+	ds := dynamodbstreams.NewFromConfig(cfg, func(o *dynamodbstreams.Options) {
+		if opts.Endpoint != "" {
+			o.BaseEndpoint = aws.String(opts.Endpoint)
+		}
+	})
+
+	checkpointer := opts.Checkpointer
+	if checkpointer == nil && opts.CheckpointTable != "" {
+		checkpointer = NewDynamoDBCheckpointer(d, opts.CheckpointTable)
+	}
+	if checkpointer == nil {
+		checkpointer = newMemCheckpointer()
+	}
+	maxConcurrentShards := opts.MaxConcurrentShards
+	if maxConcurrentShards <= 0 {
+		maxConcurrentShards = DefaultMaxConcurrentShards
+	}
+	pollInterval := opts.PollInterval
+	if pollInterval <= 0 {
+		pollInterval = DefaultPollInterval
+	}
+	backoffBase := opts.BackoffBase
+	if backoffBase <= 0 {
+		backoffBase = DefaultBackoffBase
+	}
+	backoffMax := opts.BackoffMax
+	if backoffMax <= 0 {
+		backoffMax = DefaultBackoffMax
+	}
+	outputFormat := opts.OutputFormat
+	if outputFormat == "" {
+		outputFormat = OutputFormatDDBJSON
+	}
+	filter, err := NewFilter(opts.FilterEventNames, opts.FilterExpr)
+	if err != nil {
+		return nil, err
+	}
+
 	a := &App{
-		table:   table,
-		command: command,
-		args:    strings.Split(args, ","),
-		d:       dynamodb.New(sess),
-		ds:      dynamodbstreams.New(sess),
+		table:               opts.Table,
+		d:                   d,
+		ds:                  ds,
+		batcher:             newBatcher(opts.Sink, checkpointer, opts.Batch),
+		checkpointer:        checkpointer,
+		maxConcurrentShards: maxConcurrentShards,
+		pollInterval:        pollInterval,
+		backoffBase:         backoffBase,
+		backoffMax:          backoffMax,
+		outputFormat:        outputFormat,
+		filter:              filter,
 	}
 	return a, nil
 }
 
-func (a *App) Run() error {
-	log.Println("run dynamodb streams piper")
-	dto, err := a.d.DescribeTable(&dynamodb.DescribeTableInput{
+// Run drains the table's stream until ctx is cancelled (e.g. on
+// SIGINT/SIGTERM, see cmd/pipe), then flushes and closes the batcher's
+// Sink before returning.
+func (a *App) Run(ctx context.Context) error {
+	slog.InfoContext(ctx, "run dynamodb streams piper")
+	dto, err := a.d.DescribeTable(ctx, &dynamodb.DescribeTableInput{
 		TableName: aws.String(a.table),
 	})
 	if err != nil {
 		return errors.Wrap(err, "failed describe table")
 	}
-	if *dto.Table.LatestStreamArn == "" {
+	if aws.ToString(dto.Table.LatestStreamArn) == "" {
 		return errors.New("disable dynamodb streams")
 	}
-	dso, err := a.ds.DescribeStream(&dynamodbstreams.DescribeStreamInput{
-		StreamArn: dto.Table.LatestStreamArn,
-	})
-	if err != nil {
-		return errors.Wrap(err, "failed describe stream")
+	streamArn := dto.Table.LatestStreamArn
+
+	mgr := newShardManager(a.ds, streamArn, a.checkpointer, func(ctx context.Context, shardID string, r types.Record) error {
+		return a.dispatch(ctx, streamArn, shardID, r)
+	}, a.maxConcurrentShards, a.pollInterval, a.backoffBase, a.backoffMax)
+
+	runErr := mgr.run(ctx)
+
+	if err := a.batcher.Close(context.Background()); err != nil {
+		if runErr == nil {
+			return err
+		}
+		slog.Error("failed to close sink", "error", err)
 	}
-	gsio, err := a.ds.GetShardIterator(&dynamodbstreams.GetShardIteratorInput{
-		ShardId:           dso.StreamDescription.Shards[len(dso.StreamDescription.Shards)-1].ShardId,
-		StreamArn:         dto.Table.LatestStreamArn,
-		ShardIteratorType: aws.String(dynamodbstreams.ShardIteratorTypeTrimHorizon),
-	})
+	return runErr
+}
+
+// dispatch converts a single stream record into the tool's Record type
+// and hands it to the batcher, which groups it with others before
+// delivering it to the configured Sink and advancing shardID's
+// checkpoint. Records rejected by a.filter never reach the batcher.
+func (a *App) dispatch(ctx context.Context, streamArn *string, shardID string, r types.Record) error {
+	keep, err := a.filter.Keep(r)
 	if err != nil {
-		return errors.Wrap(err, "failed get shard iterator")
-	}
-	itr := gsio.ShardIterator
-	for {
-		log.Printf("iterator %s", *itr)
-		gro, err := a.ds.GetRecords(&dynamodbstreams.GetRecordsInput{
-			ShardIterator: itr,
-		})
-		if err != nil {
-			return errors.Wrap(err, "failed get records")
-		}
-		itr = gro.NextShardIterator
-		log.Printf("num records: %d", len(gro.Records))
-		for _, r := range gro.Records {
-			records := &DynamoDBEvent{}
-			nr := NewRecord(r)
-			nr.EventSourceArn = dto.Table.LatestStreamArn
-			records.Records = append(records.Records, nr)
-			rj, _ := json.MarshalIndent(records, "", "  ")
-			log.Printf("record: \n%s", rj)
-			cmd := exec.Command(a.command, a.args...)
-			stdin, _ := cmd.StdinPipe()
-			io.WriteString(stdin, string(rj))
-			stdin.Close()
-			out, err := cmd.CombinedOutput()
-			if err != nil {
-				return errors.Wrap(err, "failed cmd")
-			}
-			log.Printf("cmd results: %s", out)
-			time.Sleep(5 * time.Second)
-		}
-		if *itr == "" {
-			break
-		}
-		time.Sleep(5 * time.Second)
+		return errors.Wrap(err, "failed to evaluate filter")
 	}
-	return nil
+	if !keep {
+		return nil
+	}
+	recordsProcessedTotal.WithLabelValues(string(r.EventName)).Inc()
+
+	nr := NewRecord(r, a.outputFormat)
+	nr.EventSourceArn = streamArn
+	return a.batcher.Add(ctx, shardID, nr)
 }
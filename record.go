@@ -1,10 +1,9 @@
 package pipe
 
 import (
-	"github.com/aws/aws-sdk-go/aws"
-	"github.com/aws/aws-sdk-go/aws/awsutil"
-	"github.com/aws/aws-sdk-go/service/dynamodb"
-	"github.com/aws/aws-sdk-go/service/dynamodbstreams"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
 )
 
 // The DynamoDBEvent stream event handled to Lambda
@@ -13,18 +12,24 @@ type DynamoDBEvent struct {
 	Records []*Record `json:"Records"`
 }
 
+// MarshalEvent renders records as the same DynamoDBEvent JSON shape Lambda
+// hands a stream-triggered function, for Sinks that want to reproduce it.
+func MarshalEvent(records []*Record) ([]byte, error) {
+	return json.MarshalIndent(&DynamoDBEvent{Records: records}, "", "  ")
+}
+
 // A description of a unique event within a stream.
 type Record struct {
 	// The region in which the GetRecords request was received.
-	AwsRegion *string `locationName:"awsRegion" type:"string"`
+	AwsRegion *string `json:"awsRegion,omitempty"`
 
 	// The main body of the stream record, containing all of the DynamoDB-specific
 	// fields.
-	Dynamodb *StreamRecord `locationName:"dynamodb" type:"structure"`
+	Dynamodb *StreamRecord `json:"dynamodb,omitempty"`
 
 	// A globally unique identifier for the event that was recorded in this stream
 	// record.
-	EventID *string `locationName:"eventID" type:"string"`
+	EventID *string `json:"eventID,omitempty"`
 
 	// The type of data modification that was performed on the DynamoDB table:
 	//
@@ -33,11 +38,11 @@ type Record struct {
 	//    * MODIFY - one or more of an existing item's attributes were modified.
 	//
 	//    * REMOVE - the item was deleted from the table
-	EventName *string `locationName:"eventName" type:"string" enum:"OperationType"`
+	EventName types.OperationType `json:"eventName,omitempty"`
 
 	// The AWS service from which the stream record originated. For DynamoDB Streams,
 	// this is aws:dynamodb.
-	EventSource *string `locationName:"eventSource" type:"string"`
+	EventSource *string `json:"eventSource,omitempty"`
 
 	// The version number of the stream record format. This number is updated whenever
 	// the structure of Record is modified.
@@ -45,11 +50,11 @@ type Record struct {
 	// Client applications must not assume that eventVersion will remain at a particular
 	// value, as this number is subject to change at any time. In general, eventVersion
 	// will only increase as the low-level DynamoDB Streams API evolves.
-	EventVersion *string `locationName:"eventVersion" type:"string"`
+	EventVersion *string `json:"eventVersion,omitempty"`
 
 	// The event source ARN of DynamoDB
 	// "arn:aws:dynamodb:us-east-1:123456789012:table/ExampleTableWithStream/stream/2015-06-27T00:48:05.899"
-	EventSourceArn *string `json:"eventSourceARN"`
+	EventSourceArn *string `json:"eventSourceARN,omitempty"`
 
 	// Items that are deleted by the Time to Live process after expiration have
 	// the following fields:
@@ -57,23 +62,31 @@ type Record struct {
 	//    * Records[].userIdentity.type "Service"
 	//
 	//    * Records[].userIdentity.principalId "dynamodb.amazonaws.com"
-	UserIdentity *dynamodbstreams.Identity `locationName:"userIdentity" type:"structure"`
+	UserIdentity *types.Identity `json:"userIdentity,omitempty"`
 }
 
-// String returns the string representation
-func (s Record) String() string {
-	return awsutil.Prettify(s)
-}
-
-// GoString returns the string representation
-func (s Record) GoString() string {
-	return s.String()
-}
+// OutputFormat selects how a Record's Keys/NewImage/OldImage attributes
+// are rendered.
+type OutputFormat string
+
+const (
+	// OutputFormatDDBJSON reproduces the DynamoDB-wire-format wrapper
+	// shape Lambda hands a stream-triggered function, e.g. {"S": "foo"}.
+	OutputFormatDDBJSON OutputFormat = "ddb-json"
+	// OutputFormatPlainJSON unwraps attributes into native JSON types
+	// (strings, numbers, bools, lists, maps), for sinks that would
+	// rather not deal with the DynamoDB type wrappers.
+	OutputFormatPlainJSON OutputFormat = "plain-json"
+	// OutputFormatBoth renders Keys/NewImage/OldImage in ddb-json form
+	// and additionally populates the KeysPlain/NewImagePlain/OldImagePlain
+	// fields with the plain-json rendering.
+	OutputFormatBoth OutputFormat = "both"
+)
 
-func NewRecord(r *dynamodbstreams.Record) *Record {
+func NewRecord(r types.Record, format OutputFormat) *Record {
 	return &Record{
 		AwsRegion:    r.AwsRegion,
-		Dynamodb:     NewStreamRecord(r.Dynamodb),
+		Dynamodb:     NewStreamRecord(r.Dynamodb, format),
 		EventID:      r.EventID,
 		EventName:    r.EventName,
 		EventSource:  r.EventSource,
@@ -87,22 +100,29 @@ func NewRecord(r *dynamodbstreams.Record) *Record {
 type StreamRecord struct {
 	// The approximate date and time when the stream record was created, in UNIX
 	// epoch time (http://www.epochconverter.com/) format.
-	ApproximateCreationDateTime *int64 `type:"long"`
+	ApproximateCreationDateTime *int64 `json:"ApproximateCreationDateTime,omitempty"`
 
 	// The primary key attribute(s) for the DynamoDB item that was modified.
-	Keys map[string]interface{} `type:"map" json:",omitempty"`
+	Keys map[string]interface{} `json:",omitempty"`
 
 	// The item in the DynamoDB table as it appeared after it was modified.
-	NewImage map[string]interface{} `type:"map" json:",omitempty"`
+	NewImage map[string]interface{} `json:",omitempty"`
 
 	// The item in the DynamoDB table as it appeared before it was modified.
-	OldImage map[string]interface{} `type:"map" json:",omitempty"`
+	OldImage map[string]interface{} `json:",omitempty"`
+
+	// KeysPlain/NewImagePlain/OldImagePlain hold the plain-json rendering
+	// of Keys/NewImage/OldImage alongside the ddb-json fields above. Only
+	// populated when OutputFormatBoth is selected.
+	KeysPlain     map[string]interface{} `json:"KeysPlain,omitempty"`
+	NewImagePlain map[string]interface{} `json:"NewImagePlain,omitempty"`
+	OldImagePlain map[string]interface{} `json:"OldImagePlain,omitempty"`
 
 	// The sequence number of the stream record.
-	SequenceNumber *string `min:"21" type:"string"`
+	SequenceNumber *string `json:"SequenceNumber,omitempty"`
 
 	// The size of the stream record, in bytes.
-	SizeBytes *int64 `min:"1" type:"long"`
+	SizeBytes *int64 `json:"SizeBytes,omitempty"`
 
 	// The type of data from the modified DynamoDB item that was captured in this
 	// stream record:
@@ -114,138 +134,189 @@ type StreamRecord struct {
 	//    * OLD_IMAGE - the entire item, as it appeared before it was modified.
 	//
 	//    * NEW_AND_OLD_IMAGES - both the new and the old item images of the item.
-	StreamViewType *string `type:"string" enum:"StreamViewType"`
+	StreamViewType types.StreamViewType `json:"StreamViewType,omitempty"`
 }
 
-func NewStreamRecord(r *dynamodbstreams.StreamRecord) *StreamRecord {
-	return &StreamRecord{
-		ApproximateCreationDateTime: aws.Int64(r.ApproximateCreationDateTime.Unix()),
-		Keys:                        NewAttributeValueMap(r.Keys),
-		NewImage:                    NewAttributeValueMap(r.NewImage),
-		OldImage:                    NewAttributeValueMap(r.OldImage),
+func NewStreamRecord(r *types.StreamRecord, format OutputFormat) *StreamRecord {
+	if r == nil {
+		return nil
+	}
+	var createdAt *int64
+	if r.ApproximateCreationDateTime != nil {
+		unix := r.ApproximateCreationDateTime.Unix()
+		createdAt = &unix
+	}
+	sr := &StreamRecord{
+		ApproximateCreationDateTime: createdAt,
 		SequenceNumber:              r.SequenceNumber,
 		SizeBytes:                   r.SizeBytes,
+		StreamViewType:              r.StreamViewType,
 	}
-}
-
-// String returns the string representation
-func (s StreamRecord) String() string {
-	return awsutil.Prettify(s)
-}
-
-// GoString returns the string representation
-func (s StreamRecord) GoString() string {
-	return s.String()
+	switch format {
+	case OutputFormatPlainJSON:
+		sr.Keys = NewPlainAttributeValueMap(r.Keys)
+		sr.NewImage = NewPlainAttributeValueMap(r.NewImage)
+		sr.OldImage = NewPlainAttributeValueMap(r.OldImage)
+	case OutputFormatBoth:
+		sr.Keys = NewAttributeValueMap(r.Keys)
+		sr.NewImage = NewAttributeValueMap(r.NewImage)
+		sr.OldImage = NewAttributeValueMap(r.OldImage)
+		sr.KeysPlain = NewPlainAttributeValueMap(r.Keys)
+		sr.NewImagePlain = NewPlainAttributeValueMap(r.NewImage)
+		sr.OldImagePlain = NewPlainAttributeValueMap(r.OldImage)
+	default:
+		sr.Keys = NewAttributeValueMap(r.Keys)
+		sr.NewImage = NewAttributeValueMap(r.NewImage)
+		sr.OldImage = NewAttributeValueMap(r.OldImage)
+	}
+	return sr
 }
 
 type AttributeValueB struct {
-	B []byte `type:"blob"`
+	B []byte `json:"B"`
 }
 
 type AttributeValueBOOL struct {
-	BOOL *bool `type:"boolean"`
+	BOOL bool `json:"BOOL"`
 }
 
 type AttributeValueBS struct {
-	BS [][]byte `type:"list"`
+	BS [][]byte `json:"BS"`
 }
 
 type AttributeValueL struct {
-	L []interface{} `type:"list"`
+	L []interface{} `json:"L"`
 }
 
 type AttributeValueM struct {
-	M map[string]interface{} `type:"map"`
+	M map[string]interface{} `json:"M"`
 }
 
 type AttributeValueN struct {
-	N *string
+	N string `json:"N"`
 }
 
 type AttributeValueNS struct {
-	NS []*string `type:"list"`
+	NS []string `json:"NS"`
 }
 
 type AttributeValueNULL struct {
-	NULL *bool `type:"boolean"`
+	NULL bool `json:"NULL"`
 }
 
 type AttributeValueS struct {
-	S *string
+	S string `json:"S"`
 }
 
 type AttributeValueSS struct {
-	SS []*string `type:"list"`
+	SS []string `json:"SS"`
 }
 
-func NewAttributeValue(a *dynamodb.AttributeValue) interface{} {
-	if a.B != nil {
-		return AttributeValueB{
-			B: a.B,
-		}
+// NewAttributeValue converts a single v2 AttributeValue (a sealed
+// interface implemented by one AttributeValueMember* type per DynamoDB
+// type) into the DynamoDB-wire-format wrapper Lambda consumers expect,
+// e.g. {"S": "foo"}. Unlike the v1 struct-of-pointers representation,
+// the member type tells us exactly which case we're in, so no nil checks
+// are needed.
+func NewAttributeValue(a types.AttributeValue) interface{} {
+	switch v := a.(type) {
+	case *types.AttributeValueMemberB:
+		return AttributeValueB{B: v.Value}
+	case *types.AttributeValueMemberBOOL:
+		return AttributeValueBOOL{BOOL: v.Value}
+	case *types.AttributeValueMemberBS:
+		return AttributeValueBS{BS: v.Value}
+	case *types.AttributeValueMemberL:
+		return AttributeValueL{L: NewAttributeValueList(v.Value)}
+	case *types.AttributeValueMemberM:
+		return AttributeValueM{M: NewAttributeValueMap(v.Value)}
+	case *types.AttributeValueMemberN:
+		return AttributeValueN{N: v.Value}
+	case *types.AttributeValueMemberNS:
+		return AttributeValueNS{NS: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return AttributeValueNULL{NULL: v.Value}
+	case *types.AttributeValueMemberS:
+		return AttributeValueS{S: v.Value}
+	case *types.AttributeValueMemberSS:
+		return AttributeValueSS{SS: v.Value}
+	default:
+		return nil
 	}
-	if a.BOOL != nil {
-		return AttributeValueBOOL{
-			BOOL: a.BOOL,
-		}
-	}
-	if a.BS != nil {
-		return AttributeValueBS{
-			BS: a.BS,
-		}
-	}
-	if a.N != nil {
-		return AttributeValueN{
-			N: a.N,
-		}
-	}
-	if a.NS != nil {
-		return AttributeValueNS{
-			NS: a.NS,
-		}
-	}
-	if a.NULL != nil {
-		return AttributeValueNULL{
-			NULL: a.NULL,
-		}
-	}
-	if a.S != nil {
-		return AttributeValueS{
-			S: a.S,
-		}
+}
+
+func NewAttributeValueMap(m map[string]types.AttributeValue) map[string]interface{} {
+	if m == nil {
+		return nil
 	}
-	if a.SS != nil {
-		return AttributeValueSS{
-			SS: a.SS,
-		}
+	r := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		r[k] = NewAttributeValue(v)
 	}
-	if a.L != nil {
-		return AttributeValueL{
-			L: NewAttributeValueList(a.L),
-		}
+	return r
+}
+
+func NewAttributeValueList(l []types.AttributeValue) []interface{} {
+	r := make([]interface{}, len(l))
+	for idx, a := range l {
+		r[idx] = NewAttributeValue(a)
 	}
-	if a.M != nil {
-		return AttributeValueM{
-			M: NewAttributeValueMap(a.M),
+	return r
+}
+
+// NewPlainAttributeValue converts a single v2 AttributeValue into a
+// native Go value (string, json.Number, bool, []byte, []interface{} or
+// map[string]interface{}; [][]byte, []json.Number or []string for the
+// BS/NS/SS set types; nil for NULL) instead of the ddb-json wrapper shape
+// NewAttributeValue produces. []byte values are marshaled to base64
+// strings by encoding/json automatically, same as the B/BS wrapper
+// fields do today.
+func NewPlainAttributeValue(a types.AttributeValue) interface{} {
+	switch v := a.(type) {
+	case *types.AttributeValueMemberB:
+		return v.Value
+	case *types.AttributeValueMemberBOOL:
+		return v.Value
+	case *types.AttributeValueMemberBS:
+		return v.Value
+	case *types.AttributeValueMemberL:
+		return NewPlainAttributeValueList(v.Value)
+	case *types.AttributeValueMemberM:
+		return NewPlainAttributeValueMap(v.Value)
+	case *types.AttributeValueMemberN:
+		return json.Number(v.Value)
+	case *types.AttributeValueMemberNS:
+		ns := make([]json.Number, len(v.Value))
+		for i, n := range v.Value {
+			ns[i] = json.Number(n)
 		}
+		return ns
+	case *types.AttributeValueMemberNULL:
+		return nil
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberSS:
+		return v.Value
+	default:
+		return nil
 	}
-	return nil
 }
 
-func NewAttributeValueMap(m map[string]*dynamodb.AttributeValue) map[string]interface{} {
-	r := make(map[string]interface{})
+func NewPlainAttributeValueMap(m map[string]types.AttributeValue) map[string]interface{} {
+	if m == nil {
+		return nil
+	}
+	r := make(map[string]interface{}, len(m))
 	for k, v := range m {
-		vv := v
-		r[k] = NewAttributeValue(vv)
+		r[k] = NewPlainAttributeValue(v)
 	}
 	return r
 }
 
-func NewAttributeValueList(l []*dynamodb.AttributeValue) []interface{} {
+func NewPlainAttributeValueList(l []types.AttributeValue) []interface{} {
 	r := make([]interface{}, len(l))
 	for idx, a := range l {
-		aa := a
-		r[idx] = NewAttributeValue(aa)
+		r[idx] = NewPlainAttributeValue(a)
 	}
 	return r
 }
@@ -0,0 +1,280 @@
+package pipe
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+	"github.com/pkg/errors"
+)
+
+// RecordHandler is called once per stream record, in order, for a single
+// shard, identified by shardID. Shards are processed concurrently, so a
+// RecordHandler may be called concurrently from different shards and
+// must be safe for that.
+type RecordHandler func(ctx context.Context, shardID string, r types.Record) error
+
+// shardManager concurrently drains every shard of a stream, respecting the
+// parent/child shard DAG exposed by DescribeStream: a child shard is only
+// started once its parent has reached end-of-shard (or the parent is no
+// longer present, e.g. it already expired off the 24h stream). It
+// periodically re-polls DescribeStream so shards created by a resharding
+// event after startup are picked up without a restart. Polling and every
+// AWS call it makes stop as soon as ctx is cancelled.
+type shardManager struct {
+	ds           *dynamodbstreams.Client
+	streamArn    *string
+	checkpointer Checkpointer
+	handle       RecordHandler
+
+	maxConcurrentShards int
+	pollInterval        time.Duration
+	backoffBase         time.Duration
+	backoffMax          time.Duration
+
+	mu      sync.Mutex
+	started map[string]bool
+	ended   map[string]bool
+	sem     chan struct{}
+	wg      sync.WaitGroup
+	errOnce sync.Once
+	runErr  error
+	cancel  context.CancelFunc
+}
+
+func newShardManager(ds *dynamodbstreams.Client, streamArn *string, checkpointer Checkpointer, handle RecordHandler, maxConcurrentShards int, pollInterval, backoffBase, backoffMax time.Duration) *shardManager {
+	return &shardManager{
+		ds:                  ds,
+		streamArn:           streamArn,
+		checkpointer:        checkpointer,
+		handle:              handle,
+		maxConcurrentShards: maxConcurrentShards,
+		pollInterval:        pollInterval,
+		backoffBase:         backoffBase,
+		backoffMax:          backoffMax,
+		started:             map[string]bool{},
+		ended:               map[string]bool{},
+		sem:                 make(chan struct{}, maxConcurrentShards),
+	}
+}
+
+// run polls DescribeStream until ctx is cancelled or a shard fails
+// irrecoverably, then waits for every in-flight shard goroutine to drain
+// before returning. A cancelled ctx is not itself treated as an error.
+//
+// run derives its own cancellable context from ctx and cancels it as
+// soon as any shard (or DescribeStream itself) fails, so sibling shards
+// still polling GetRecords stop promptly instead of leaving wg.Wait()
+// blocked on goroutines nothing ever told to quit.
+func (m *shardManager) run(ctx context.Context) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	m.mu.Lock()
+	m.cancel = cancel
+	m.mu.Unlock()
+	defer cancel()
+
+	for {
+		if err := m.pollOnce(runCtx); err != nil && runCtx.Err() == nil {
+			m.fail(err)
+			m.wg.Wait()
+			return m.runErr
+		}
+		if m.failed() {
+			m.wg.Wait()
+			return m.runErr
+		}
+		select {
+		case <-runCtx.Done():
+			m.wg.Wait()
+			return m.failedOr(nil)
+		case <-time.After(m.pollInterval):
+		}
+	}
+}
+
+func (m *shardManager) failed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.runErr != nil
+}
+
+func (m *shardManager) failedOr(fallback error) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.runErr != nil {
+		return m.runErr
+	}
+	return fallback
+}
+
+// fail records the first irrecoverable error reported by pollOnce or a
+// shard goroutine and cancels run's internal context, so every other
+// shard goroutine still polling GetRecords stops promptly instead of
+// running until the process is killed.
+func (m *shardManager) fail(err error) {
+	m.errOnce.Do(func() {
+		m.mu.Lock()
+		m.runErr = err
+		cancel := m.cancel
+		m.mu.Unlock()
+		if cancel != nil {
+			cancel()
+		}
+	})
+}
+
+// pollOnce describes the stream, paging through every DescribeStream
+// result until LastEvaluatedShardId is empty, and starts a goroutine for
+// every shard that is newly eligible to run.
+func (m *shardManager) pollOnce(ctx context.Context) error {
+	var shards []types.Shard
+	var exclusiveStartShardID *string
+	for {
+		dso, err := m.ds.DescribeStream(ctx, &dynamodbstreams.DescribeStreamInput{
+			StreamArn:             m.streamArn,
+			ExclusiveStartShardId: exclusiveStartShardID,
+		})
+		if err != nil {
+			return errors.Wrap(err, "failed describe stream")
+		}
+		shards = append(shards, dso.StreamDescription.Shards...)
+		if aws.ToString(dso.StreamDescription.LastEvaluatedShardId) == "" {
+			break
+		}
+		exclusiveStartShardID = dso.StreamDescription.LastEvaluatedShardId
+	}
+
+	started := map[string]bool{}
+	ended := map[string]bool{}
+	m.mu.Lock()
+	for id, v := range m.started {
+		started[id] = v
+	}
+	for id, v := range m.ended {
+		ended[id] = v
+	}
+	m.mu.Unlock()
+
+	for _, s := range shards {
+		if !shardEligible(s, shards, started, ended) {
+			continue
+		}
+		id := aws.ToString(s.ShardId)
+		m.mu.Lock()
+		m.started[id] = true
+		m.mu.Unlock()
+		shard := s
+		m.wg.Add(1)
+		go func() {
+			defer m.wg.Done()
+			if err := m.consumeShard(ctx, shard); err != nil && ctx.Err() == nil {
+				m.fail(errors.Wrapf(err, "shard %s", aws.ToString(shard.ShardId)))
+			}
+		}()
+	}
+	return nil
+}
+
+// shardEligible reports whether s is ready to have a consumeShard
+// goroutine started for it: it must not already be started, and if it
+// has a parent, that parent must either be absent from the current
+// DescribeStream response (already expired off the stream) or have
+// reached end-of-shard.
+func shardEligible(s types.Shard, shards []types.Shard, started, ended map[string]bool) bool {
+	id := aws.ToString(s.ShardId)
+	if started[id] {
+		return false
+	}
+	if s.ParentShardId == nil {
+		return true
+	}
+	parentID := aws.ToString(s.ParentShardId)
+	for _, p := range shards {
+		if aws.ToString(p.ShardId) == parentID {
+			return ended[parentID]
+		}
+	}
+	return true
+}
+
+// consumeShard drains a single shard from its checkpoint (or TRIM_HORIZON
+// if unset) until it reaches end-of-shard or ctx is cancelled.
+func (m *shardManager) consumeShard(ctx context.Context, shard types.Shard) error {
+	m.sem <- struct{}{}
+	defer func() { <-m.sem }()
+
+	id := aws.ToString(shard.ShardId)
+	slog.InfoContext(ctx, "starting shard", "shard_id", id)
+
+	iteratorType := types.ShardIteratorTypeTrimHorizon
+	var startingSequenceNumber *string
+	seq, err := m.checkpointer.Get(ctx, id)
+	if err != nil {
+		return errors.Wrap(err, "failed to load checkpoint")
+	}
+	if seq != "" {
+		iteratorType = types.ShardIteratorTypeAfterSequenceNumber
+		startingSequenceNumber = aws.String(seq)
+	}
+
+	gsio, err := m.ds.GetShardIterator(ctx, &dynamodbstreams.GetShardIteratorInput{
+		StreamArn:         m.streamArn,
+		ShardId:           shard.ShardId,
+		ShardIteratorType: iteratorType,
+		SequenceNumber:    startingSequenceNumber,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed get shard iterator")
+	}
+
+	itr := gsio.ShardIterator
+	b := newBackoff(m.backoffBase, m.backoffMax)
+	for itr != nil && *itr != "" {
+		if ctx.Err() != nil {
+			return nil
+		}
+		start := time.Now()
+		gro, err := m.ds.GetRecords(ctx, &dynamodbstreams.GetRecordsInput{
+			ShardIterator: itr,
+		})
+		getRecordsDurationSeconds.Observe(time.Since(start).Seconds())
+		if err != nil {
+			return errors.Wrap(err, "failed get records")
+		}
+		for _, r := range gro.Records {
+			if err := m.handle(ctx, id, r); err != nil {
+				return errors.Wrap(err, "failed to handle record")
+			}
+			observeIteratorAge(id, r.Dynamodb.ApproximateCreationDateTime)
+		}
+		itr = gro.NextShardIterator
+		if len(gro.Records) == 0 {
+			if err := sleep(ctx, b.Next()); err != nil {
+				return nil
+			}
+		} else {
+			b.Reset()
+		}
+	}
+
+	slog.InfoContext(ctx, "shard reached end-of-shard", "shard_id", id)
+	m.mu.Lock()
+	m.ended[id] = true
+	m.mu.Unlock()
+	return nil
+}
+
+// sleep waits for d, returning early with ctx.Err() if ctx is cancelled
+// first.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
@@ -0,0 +1,63 @@
+package pipe
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodbstreams/types"
+)
+
+func TestShardEligible(t *testing.T) {
+	shards := []types.Shard{
+		{ShardId: aws.String("parent")},
+		{ShardId: aws.String("child"), ParentShardId: aws.String("parent")},
+		{ShardId: aws.String("orphan"), ParentShardId: aws.String("long-gone")},
+	}
+
+	cases := []struct {
+		name    string
+		shard   types.Shard
+		started map[string]bool
+		ended   map[string]bool
+		want    bool
+	}{
+		{
+			name:  "no parent starts immediately",
+			shard: shards[0],
+			want:  true,
+		},
+		{
+			name:  "child waits while parent is still open",
+			shard: shards[1],
+			ended: map[string]bool{},
+			want:  false,
+		},
+		{
+			name:  "child starts once parent has ended",
+			shard: shards[1],
+			ended: map[string]bool{"parent": true},
+			want:  true,
+		},
+		{
+			name:  "child starts once parent has expired off the stream",
+			shard: shards[2],
+			ended: map[string]bool{},
+			want:  true,
+		},
+		{
+			name:    "already-started shard is never restarted",
+			shard:   shards[0],
+			started: map[string]bool{"parent": true},
+			want:    false,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := shardEligible(tc.shard, shards, tc.started, tc.ended)
+			if got != tc.want {
+				t.Fatalf("shardEligible() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
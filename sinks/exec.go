@@ -0,0 +1,53 @@
+// Package sinks provides Sink implementations for delivering stream
+// records somewhere other than a per-record child process.
+package sinks
+
+import (
+	"context"
+	"io"
+	"log"
+	"os/exec"
+
+	dp "github.com/gino8070/dynamodb-stream-pipe"
+	"github.com/pkg/errors"
+)
+
+// Exec runs a fresh command invocation per batch, feeding the batch as
+// Lambda-shaped DynamoDBEvent JSON on stdin. With the default batch size
+// of one this reproduces the tool's original per-record exec behaviour.
+type Exec struct {
+	Command string
+	Args    []string
+}
+
+// NewExec returns a Sink that execs command with args for every batch.
+func NewExec(command string, args []string) *Exec {
+	return &Exec{Command: command, Args: args}
+}
+
+// Write implements pipe.Sink.
+func (s *Exec) Write(ctx context.Context, records []*dp.Record) error {
+	rj, err := dp.MarshalEvent(records)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+	cmd := exec.CommandContext(ctx, s.Command, s.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return errors.Wrap(err, "failed to open stdin")
+	}
+	io.WriteString(stdin, string(rj))
+	stdin.Close()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return errors.Wrap(err, "failed cmd")
+	}
+	log.Printf("cmd results: %s", out)
+	return nil
+}
+
+// Flush implements pipe.Sink. Exec has nothing to flush between batches.
+func (s *Exec) Flush(ctx context.Context) error { return nil }
+
+// Close implements pipe.Sink. Exec holds no resources between batches.
+func (s *Exec) Close() error { return nil }
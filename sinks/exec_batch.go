@@ -0,0 +1,83 @@
+package sinks
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"sync"
+
+	dp "github.com/gino8070/dynamodb-stream-pipe"
+	"github.com/pkg/errors"
+)
+
+// ExecBatch starts command once and keeps it running for the life of the
+// pipe, feeding it one JSON object per line (JSONL) per record on every
+// batch instead of forking a new process each time. Use this when the
+// per-record exec cost (process fork, interpreter startup, ...) dominates
+// the work.
+type ExecBatch struct {
+	mu    sync.Mutex
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+}
+
+// NewExecBatch starts command with args and returns a Sink that streams
+// batches to its stdin as they arrive.
+func NewExecBatch(command string, args []string) (*ExecBatch, error) {
+	cmd := exec.Command(command, args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open stdin")
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open stdout")
+	}
+	cmd.Stderr = os.Stderr
+	if err := cmd.Start(); err != nil {
+		return nil, errors.Wrap(err, "failed to start cmd")
+	}
+
+	go func() {
+		sc := bufio.NewScanner(stdout)
+		for sc.Scan() {
+			log.Printf("cmd output: %s", sc.Text())
+		}
+	}()
+
+	return &ExecBatch{cmd: cmd, stdin: stdin}, nil
+}
+
+// Write implements pipe.Sink.
+func (s *ExecBatch) Write(ctx context.Context, records []*dp.Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	enc := json.NewEncoder(s.stdin)
+	for _, r := range records {
+		if err := enc.Encode(r); err != nil {
+			return errors.Wrap(err, "failed to write record")
+		}
+	}
+	return nil
+}
+
+// Flush implements pipe.Sink. The command reads from stdin as it's
+// written, so there is nothing to flush between batches.
+func (s *ExecBatch) Flush(ctx context.Context) error { return nil }
+
+// Close closes the command's stdin and waits for it to exit.
+func (s *ExecBatch) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.stdin.Close(); err != nil {
+		return errors.Wrap(err, "failed to close stdin")
+	}
+	if err := s.cmd.Wait(); err != nil {
+		return errors.Wrap(err, "cmd exited with error")
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package sinks
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"time"
+
+	dp "github.com/gino8070/dynamodb-stream-pipe"
+	"github.com/pkg/errors"
+)
+
+// DefaultHTTPTimeout bounds a single webhook request attempt.
+const DefaultHTTPTimeout = 30 * time.Second
+
+// HTTP delivers each batch as a single webhook call carrying the same
+// DynamoDBEvent JSON shape Lambda would hand a stream-triggered function.
+type HTTP struct {
+	URL     string
+	Method  string
+	Headers map[string]string
+	Retries int
+
+	client *http.Client
+}
+
+// NewHTTP returns a Sink that POSTs (or, if method is non-empty, sends
+// with that method) every batch to url, retrying up to retries times on
+// network errors or non-2xx responses.
+func NewHTTP(url, method string, headers map[string]string, retries int) *HTTP {
+	if method == "" {
+		method = http.MethodPost
+	}
+	return &HTTP{
+		URL:     url,
+		Method:  method,
+		Headers: headers,
+		Retries: retries,
+		client:  &http.Client{Timeout: DefaultHTTPTimeout},
+	}
+}
+
+// Write implements pipe.Sink.
+func (s *HTTP) Write(ctx context.Context, records []*dp.Record) error {
+	body, err := dp.MarshalEvent(records)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal event")
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= s.Retries; attempt++ {
+		if attempt > 0 {
+			if err := sleep(ctx, time.Duration(attempt)*time.Second); err != nil {
+				return err
+			}
+		}
+		if err := s.post(ctx, body); err != nil {
+			lastErr = err
+			continue
+		}
+		return nil
+	}
+	return errors.Wrap(lastErr, "failed to deliver webhook after retries")
+}
+
+func (s *HTTP) post(ctx context.Context, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, s.Method, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "failed to build request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range s.Headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.Wrap(err, "failed to send request")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Flush implements pipe.Sink.
+func (s *HTTP) Flush(ctx context.Context) error { return nil }
+
+// Close implements pipe.Sink.
+func (s *HTTP) Close() error { return nil }
+
+// sleep waits for d, returning early with ctx.Err() if ctx is cancelled
+// first.
+func sleep(ctx context.Context, d time.Duration) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
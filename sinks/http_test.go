@@ -0,0 +1,84 @@
+package sinks
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+)
+
+func TestHTTPWriteSucceedsWithoutRetry(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := NewHTTP(srv.URL, "", nil, 2)
+	if err := s.Write(context.Background(), recordsN(1)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("calls = %d, want 1 when the first attempt succeeds", calls)
+	}
+}
+
+func TestHTTPWriteRetriesOnFailureThenSucceeds(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := NewHTTP(srv.URL, "", nil, 2)
+	if err := s.Write(context.Background(), recordsN(1)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2 (one failure then a success)", calls)
+	}
+}
+
+func TestHTTPWriteExhaustsRetriesAndReturnsError(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	s := NewHTTP(srv.URL, "", nil, 1)
+	if err := s.Write(context.Background(), recordsN(1)); err == nil {
+		t.Fatal("Write: expected an error once retries are exhausted")
+	}
+	if calls != 2 {
+		t.Fatalf("calls = %d, want 2 (initial attempt + 1 retry)", calls)
+	}
+}
+
+func TestHTTPWriteSetsHeadersAndMethod(t *testing.T) {
+	var gotMethod, gotHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotMethod = r.Method
+		gotHeader = r.Header.Get("X-Custom")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	s := NewHTTP(srv.URL, http.MethodPut, map[string]string{"X-Custom": "value"}, 0)
+	if err := s.Write(context.Background(), recordsN(1)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	if gotMethod != http.MethodPut {
+		t.Fatalf("method = %q, want %q", gotMethod, http.MethodPut)
+	}
+	if gotHeader != "value" {
+		t.Fatalf("X-Custom header = %q, want %q", gotHeader, "value")
+	}
+}
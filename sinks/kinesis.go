@@ -0,0 +1,96 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis/types"
+	dp "github.com/gino8070/dynamodb-stream-pipe"
+	"github.com/pkg/errors"
+)
+
+// kinesisBatchSize is the maximum number of records PutRecords accepts
+// per call.
+const kinesisBatchSize = 500
+
+// kinesisClient is the subset of *kinesis.Client that Kinesis depends on,
+// narrowed so tests can exercise the batch-splitting logic against a fake.
+type kinesisClient interface {
+	PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error)
+}
+
+// Kinesis fans each record out to a Kinesis data stream, partitioned by
+// EventID. EventID is unique per record, so records spread across the
+// stream's shards; records for the same DynamoDB item may land in
+// different shards and are not kept in order relative to each other.
+type Kinesis struct {
+	StreamName string
+
+	k kinesisClient
+}
+
+// NewKinesis returns a Sink that puts every record onto streamName.
+// endpoint overrides the Kinesis endpoint, mirroring dp.Options.Endpoint;
+// leave it empty to use the default AWS endpoint for the session region.
+func NewKinesis(ctx context.Context, endpoint, streamName string) (*Kinesis, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load aws config")
+	}
+	return &Kinesis{
+		StreamName: streamName,
+		k: kinesis.NewFromConfig(cfg, func(o *kinesis.Options) {
+			if endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+			}
+		}),
+	}, nil
+}
+
+// Write implements pipe.Sink.
+func (s *Kinesis) Write(ctx context.Context, records []*dp.Record) error {
+	for start := 0; start < len(records); start += kinesisBatchSize {
+		end := start + kinesisBatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		if err := s.putBatch(ctx, records[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Kinesis) putBatch(ctx context.Context, records []*dp.Record) error {
+	entries := make([]types.PutRecordsRequestEntry, 0, len(records))
+	for _, r := range records {
+		data, err := json.Marshal(r)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal record")
+		}
+		entries = append(entries, types.PutRecordsRequestEntry{
+			Data:         data,
+			PartitionKey: r.EventID,
+		})
+	}
+	out, err := s.k.PutRecords(ctx, &kinesis.PutRecordsInput{
+		StreamName: aws.String(s.StreamName),
+		Records:    entries,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to put records")
+	}
+	if aws.ToInt32(out.FailedRecordCount) > 0 {
+		return errors.Errorf("%d of %d records failed to put", aws.ToInt32(out.FailedRecordCount), len(entries))
+	}
+	return nil
+}
+
+// Flush implements pipe.Sink.
+func (s *Kinesis) Flush(ctx context.Context) error { return nil }
+
+// Close implements pipe.Sink.
+func (s *Kinesis) Close() error { return nil }
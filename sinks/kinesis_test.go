@@ -0,0 +1,73 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kinesis"
+	"github.com/pkg/errors"
+)
+
+type fakeKinesisClient struct {
+	batchSizes []int
+	err        error
+	failed     int32
+}
+
+func (f *fakeKinesisClient) PutRecords(ctx context.Context, params *kinesis.PutRecordsInput, optFns ...func(*kinesis.Options)) (*kinesis.PutRecordsOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.batchSizes = append(f.batchSizes, len(params.Records))
+	return &kinesis.PutRecordsOutput{FailedRecordCount: aws.Int32(f.failed)}, nil
+}
+
+func TestKinesisWriteSplitsIntoBatchesOf500(t *testing.T) {
+	client := &fakeKinesisClient{}
+	s := &Kinesis{StreamName: "stream", k: client}
+
+	if err := s.Write(context.Background(), recordsN(1100)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := []int{500, 500, 100}
+	if len(client.batchSizes) != len(want) {
+		t.Fatalf("batchSizes = %v, want %v", client.batchSizes, want)
+	}
+	for i, size := range want {
+		if client.batchSizes[i] != size {
+			t.Fatalf("batchSizes = %v, want %v", client.batchSizes, want)
+		}
+	}
+}
+
+func TestKinesisWriteExactMultipleOfBatchSize(t *testing.T) {
+	client := &fakeKinesisClient{}
+	s := &Kinesis{StreamName: "stream", k: client}
+
+	if err := s.Write(context.Background(), recordsN(1000)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := []int{500, 500}
+	if len(client.batchSizes) != len(want) || client.batchSizes[0] != want[0] || client.batchSizes[1] != want[1] {
+		t.Fatalf("batchSizes = %v, want %v", client.batchSizes, want)
+	}
+}
+
+func TestKinesisWriteReturnsErrorOnPutFailure(t *testing.T) {
+	client := &fakeKinesisClient{err: errors.New("put failed")}
+	s := &Kinesis{StreamName: "stream", k: client}
+
+	if err := s.Write(context.Background(), recordsN(1)); err == nil {
+		t.Fatal("Write: expected an error from the failing client")
+	}
+}
+
+func TestKinesisWriteReportsPartialFailures(t *testing.T) {
+	client := &fakeKinesisClient{failed: 2}
+	s := &Kinesis{StreamName: "stream", k: client}
+
+	if err := s.Write(context.Background(), recordsN(3)); err == nil {
+		t.Fatal("Write: expected an error when some records fail to put")
+	}
+}
@@ -0,0 +1,96 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	dp "github.com/gino8070/dynamodb-stream-pipe"
+	"github.com/pkg/errors"
+)
+
+// sqsBatchSize is the maximum number of messages SendMessageBatch accepts
+// per call.
+const sqsBatchSize = 10
+
+// sqsClient is the subset of *sqs.Client that SQS depends on, narrowed so
+// tests can exercise the batch-splitting logic against a fake.
+type sqsClient interface {
+	SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error)
+}
+
+// SQS fans each record out as an individual SQS message, letting other
+// pipelines subscribe to the same stream via a queue instead of forking
+// a child process.
+type SQS struct {
+	QueueURL string
+
+	s sqsClient
+}
+
+// NewSQS returns a Sink that sends every record to the given queue.
+// endpoint overrides the SQS endpoint, mirroring dp.Options.Endpoint;
+// leave it empty to use the default AWS endpoint for the session region.
+func NewSQS(ctx context.Context, endpoint, queueURL string) (*SQS, error) {
+	cfg, err := awsconfig.LoadDefaultConfig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load aws config")
+	}
+	return &SQS{
+		QueueURL: queueURL,
+		s: sqs.NewFromConfig(cfg, func(o *sqs.Options) {
+			if endpoint != "" {
+				o.BaseEndpoint = aws.String(endpoint)
+			}
+		}),
+	}, nil
+}
+
+// Write implements pipe.Sink.
+func (s *SQS) Write(ctx context.Context, records []*dp.Record) error {
+	for start := 0; start < len(records); start += sqsBatchSize {
+		end := start + sqsBatchSize
+		if end > len(records) {
+			end = len(records)
+		}
+		if err := s.sendBatch(ctx, records[start:end], start); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *SQS) sendBatch(ctx context.Context, records []*dp.Record, startIdx int) error {
+	entries := make([]types.SendMessageBatchRequestEntry, 0, len(records))
+	for i, r := range records {
+		body, err := json.Marshal(r)
+		if err != nil {
+			return errors.Wrap(err, "failed to marshal record")
+		}
+		entries = append(entries, types.SendMessageBatchRequestEntry{
+			Id:          aws.String(strconv.Itoa(startIdx + i)),
+			MessageBody: aws.String(string(body)),
+		})
+	}
+	out, err := s.s.SendMessageBatch(ctx, &sqs.SendMessageBatchInput{
+		QueueUrl: aws.String(s.QueueURL),
+		Entries:  entries,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed to send message batch")
+	}
+	if len(out.Failed) > 0 {
+		return errors.Errorf("%d of %d messages failed to send", len(out.Failed), len(entries))
+	}
+	return nil
+}
+
+// Flush implements pipe.Sink.
+func (s *SQS) Flush(ctx context.Context) error { return nil }
+
+// Close implements pipe.Sink.
+func (s *SQS) Close() error { return nil }
@@ -0,0 +1,86 @@
+package sinks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/aws/aws-sdk-go-v2/service/sqs/types"
+	dp "github.com/gino8070/dynamodb-stream-pipe"
+	"github.com/pkg/errors"
+)
+
+type fakeSQSClient struct {
+	batchSizes []int
+	err        error
+	failed     int
+}
+
+func (f *fakeSQSClient) SendMessageBatch(ctx context.Context, params *sqs.SendMessageBatchInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageBatchOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	f.batchSizes = append(f.batchSizes, len(params.Entries))
+	out := &sqs.SendMessageBatchOutput{}
+	for i := 0; i < f.failed; i++ {
+		out.Failed = append(out.Failed, types.BatchResultErrorEntry{})
+	}
+	return out, nil
+}
+
+func recordsN(n int) []*dp.Record {
+	records := make([]*dp.Record, n)
+	for i := range records {
+		records[i] = &dp.Record{}
+	}
+	return records
+}
+
+func TestSQSWriteSplitsIntoBatchesOfTen(t *testing.T) {
+	client := &fakeSQSClient{}
+	s := &SQS{QueueURL: "queue", s: client}
+
+	if err := s.Write(context.Background(), recordsN(25)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := []int{10, 10, 5}
+	if len(client.batchSizes) != len(want) {
+		t.Fatalf("batchSizes = %v, want %v", client.batchSizes, want)
+	}
+	for i, size := range want {
+		if client.batchSizes[i] != size {
+			t.Fatalf("batchSizes = %v, want %v", client.batchSizes, want)
+		}
+	}
+}
+
+func TestSQSWriteExactMultipleOfBatchSize(t *testing.T) {
+	client := &fakeSQSClient{}
+	s := &SQS{QueueURL: "queue", s: client}
+
+	if err := s.Write(context.Background(), recordsN(20)); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := []int{10, 10}
+	if len(client.batchSizes) != len(want) || client.batchSizes[0] != want[0] || client.batchSizes[1] != want[1] {
+		t.Fatalf("batchSizes = %v, want %v", client.batchSizes, want)
+	}
+}
+
+func TestSQSWriteReturnsErrorOnSendFailure(t *testing.T) {
+	client := &fakeSQSClient{err: errors.New("send failed")}
+	s := &SQS{QueueURL: "queue", s: client}
+
+	if err := s.Write(context.Background(), recordsN(1)); err == nil {
+		t.Fatal("Write: expected an error from the failing client")
+	}
+}
+
+func TestSQSWriteReportsPartialFailures(t *testing.T) {
+	client := &fakeSQSClient{failed: 2}
+	s := &SQS{QueueURL: "queue", s: client}
+
+	if err := s.Write(context.Background(), recordsN(3)); err == nil {
+		t.Fatal("Write: expected an error when some messages fail to send")
+	}
+}
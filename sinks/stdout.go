@@ -0,0 +1,39 @@
+package sinks
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	dp "github.com/gino8070/dynamodb-stream-pipe"
+	"github.com/pkg/errors"
+)
+
+// Stdout writes each record as a line of JSON (JSONL) to the given
+// writer. It is the simplest Sink, useful for piping into jq or other
+// local tooling without spawning a child process per record.
+type Stdout struct {
+	enc *json.Encoder
+}
+
+// NewStdout returns a Sink that writes records as JSONL to w.
+func NewStdout(w io.Writer) *Stdout {
+	return &Stdout{enc: json.NewEncoder(w)}
+}
+
+// Write implements pipe.Sink.
+func (s *Stdout) Write(ctx context.Context, records []*dp.Record) error {
+	for _, r := range records {
+		if err := s.enc.Encode(r); err != nil {
+			return errors.Wrap(err, "failed to write record")
+		}
+	}
+	return nil
+}
+
+// Flush implements pipe.Sink.
+func (s *Stdout) Flush(ctx context.Context) error { return nil }
+
+// Close implements pipe.Sink. Stdout does not own w, so there is nothing
+// to close.
+func (s *Stdout) Close() error { return nil }